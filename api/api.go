@@ -0,0 +1,110 @@
+// Package api содержит HTTP сервер, принимающий письма от внешних систем
+// и кладущий их во внутренние отложенные очереди вместо прямой публикации в AMQP.
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/AdOnWeb/postmanq/common"
+)
+
+// Адрес сервера по умолчанию; токена по умолчанию нет, при пустом AuthToken авторизация не проверяется
+const (
+	defaultListenAddr = ":7020"
+)
+
+// Настройки HTTP сервера приема писем
+type Config struct {
+	// Адрес, на котором слушает сервер
+	ListenAddr string `yaml:"listenAddr"`
+
+	// Токен, который должен передаваться в заголовке Authorization: Bearer <token>
+	AuthToken string `yaml:"authToken"`
+}
+
+// Принимает решение о том, что делать с письмом дальше
+// Реализуется отправителем (sender), кладущим письмо в отложенные очереди
+type Enqueuer interface {
+	Enqueue(message *common.MailMessage) error
+}
+
+// HTTP сервер, принимающий письма на отправку и отдающий их статус
+type Server struct {
+	config   Config
+	enqueuer Enqueuer
+	statuses *statusStore
+	server   *http.Server
+}
+
+// Создает новый сервер приема писем
+func NewServer(config Config, enqueuer Enqueuer) *Server {
+	if len(config.ListenAddr) == 0 {
+		config.ListenAddr = defaultListenAddr
+	}
+
+	this := new(Server)
+	this.config = config
+	this.enqueuer = enqueuer
+	this.statuses = newStatusStore()
+	return this
+}
+
+// Статус письма, который можно получить через GET /message/{id}
+func (this *Server) Statuses() *statusStore {
+	return this.statuses
+}
+
+// Handler собирает маршруты сервера, используется как Run, так и тестами,
+// которым не нужно поднимать реальный TCP listener
+func (this *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/message", this.withAuth(this.handleSubmit))
+	mux.HandleFunc("/message/", this.withAuth(this.handleStatus))
+	return mux
+}
+
+// Запускает сервер, блокируясь до вызова Stop или ошибки
+func (this *Server) Run() error {
+	this.server = &http.Server{
+		Addr:    this.config.ListenAddr,
+		Handler: this.Handler(),
+	}
+
+	err := this.server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Останавливает сервер, дожидаясь завершения активных запросов
+func (this *Server) Stop() error {
+	if this.server == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return this.server.Shutdown(ctx)
+}
+
+// Проверяет bearer токен из заголовка Authorization
+func (this *Server) withAuth(handler http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		if len(this.config.AuthToken) > 0 {
+			header := request.Header.Get("Authorization")
+			expected := "Bearer " + this.config.AuthToken
+			// сравниваем за постоянное время, чтобы длина совпадающего префикса
+			// токена не утекала через время ответа
+			if subtle.ConstantTimeCompare([]byte(header), []byte(expected)) != 1 {
+				http.Error(writer, "invalid or missing bearer token", http.StatusUnauthorized)
+				return
+			}
+		}
+		handler(writer, request)
+	}
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/AdOnWeb/postmanq/common"
+)
+
+// Тело запроса на постановку письма в очередь
+type submitRequest struct {
+	// Отправитель
+	Envelope string `json:"envelope"`
+
+	// Получатель
+	Recipient string `json:"recipient"`
+
+	// Тело письма
+	Body string `json:"body"`
+
+	// Необязательные дополнительные заголовки письма, например Subject или
+	// Reply-To. Ключ - имя заголовка, значение - его содержимое
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// buildRawBody добавляет заголовки перед телом письма, если они переданы: дальше
+// по системе Body ожидается как письмо целиком (см. dkim.Signer, inbound.parser),
+// а не только как пользовательский текст
+func buildRawBody(headers map[string]string, body string) string {
+	if len(headers) == 0 {
+		return body
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var builder strings.Builder
+	for _, name := range names {
+		builder.WriteString(name)
+		builder.WriteString(": ")
+		builder.WriteString(headers[name])
+		builder.WriteString("\r\n")
+	}
+	builder.WriteString("\r\n")
+	builder.WriteString(body)
+	return builder.String()
+}
+
+// Ответ на успешную постановку письма в очередь
+type submitResponse struct {
+	Id int64 `json:"id"`
+}
+
+// Обрабатывает POST /message, кладет письмо во внутреннюю очередь
+func (this *Server) handleSubmit(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		http.Error(writer, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body submitRequest
+	decoder := json.NewDecoder(request.Body)
+	if err := decoder.Decode(&body); err != nil {
+		http.Error(writer, "invalid json body", http.StatusBadRequest)
+		return
+	}
+
+	if !common.EmailRegexp.MatchString(body.Envelope) {
+		http.Error(writer, "invalid envelope address", http.StatusBadRequest)
+		return
+	}
+	if !common.EmailRegexp.MatchString(body.Recipient) {
+		http.Error(writer, "invalid recipient address", http.StatusBadRequest)
+		return
+	}
+	if len(body.Body) == 0 {
+		http.Error(writer, "body is required", http.StatusBadRequest)
+		return
+	}
+
+	message := &common.MailMessage{
+		Envelope:  body.Envelope,
+		Recipient: body.Recipient,
+		Body:      buildRawBody(body.Headers, body.Body),
+	}
+	message.Init()
+
+	if err := this.enqueuer.Enqueue(message); err != nil {
+		http.Error(writer, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	this.statuses.setStatus(message.Id, StatusQueued)
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(writer).Encode(submitResponse{Id: message.Id})
+}
+
+// Ответ на запрос статуса письма
+type statusResponse struct {
+	Id     int64  `json:"id"`
+	Status string `json:"status"`
+}
+
+// Обрабатывает GET /message/{id}, отдает текущий статус письма
+func (this *Server) handleStatus(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		http.Error(writer, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	idString := strings.TrimPrefix(request.URL.Path, "/message/")
+	id, err := strconv.ParseInt(idString, 10, 64)
+	if err != nil {
+		http.Error(writer, "invalid message id", http.StatusBadRequest)
+		return
+	}
+
+	status, ok := this.statuses.getStatus(id)
+	if !ok {
+		http.Error(writer, "message not found", http.StatusNotFound)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(statusResponse{Id: id, Status: status.String()})
+}
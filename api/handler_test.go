@@ -0,0 +1,164 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AdOnWeb/postmanq/common"
+)
+
+type fakeEnqueuer struct {
+	err      error
+	enqueued []*common.MailMessage
+}
+
+func (this *fakeEnqueuer) Enqueue(message *common.MailMessage) error {
+	if this.err != nil {
+		return this.err
+	}
+	this.enqueued = append(this.enqueued, message)
+	return nil
+}
+
+func newTestServer(token string) (*Server, *fakeEnqueuer) {
+	enqueuer := new(fakeEnqueuer)
+	server := NewServer(Config{AuthToken: token}, enqueuer)
+	return server, enqueuer
+}
+
+func TestHandleSubmitRejectsMissingBearerToken(t *testing.T) {
+	server, _ := newTestServer("secret")
+
+	request := httptest.NewRequest(http.MethodPost, "/message", strings.NewReader(`{}`))
+	response := httptest.NewRecorder()
+	server.Handler().ServeHTTP(response, request)
+
+	if response.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without bearer token, got %d", response.Code)
+	}
+}
+
+func TestHandleSubmitRejectsInvalidBearerToken(t *testing.T) {
+	server, _ := newTestServer("secret")
+
+	request := httptest.NewRequest(http.MethodPost, "/message", strings.NewReader(`{}`))
+	request.Header.Set("Authorization", "Bearer wrong")
+	response := httptest.NewRecorder()
+	server.Handler().ServeHTTP(response, request)
+
+	if response.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 with invalid bearer token, got %d", response.Code)
+	}
+}
+
+func TestHandleSubmitRejectsInvalidJSON(t *testing.T) {
+	server, _ := newTestServer("")
+
+	request := httptest.NewRequest(http.MethodPost, "/message", strings.NewReader(`not json`))
+	response := httptest.NewRecorder()
+	server.Handler().ServeHTTP(response, request)
+
+	if response.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid json, got %d", response.Code)
+	}
+}
+
+func TestHandleSubmitRejectsInvalidEnvelope(t *testing.T) {
+	server, _ := newTestServer("")
+
+	body := `{"envelope":"not-an-email","recipient":"to@example.com","body":"hello"}`
+	request := httptest.NewRequest(http.MethodPost, "/message", strings.NewReader(body))
+	response := httptest.NewRecorder()
+	server.Handler().ServeHTTP(response, request)
+
+	if response.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid envelope, got %d", response.Code)
+	}
+}
+
+func TestHandleSubmitRejectsInvalidRecipient(t *testing.T) {
+	server, _ := newTestServer("")
+
+	body := `{"envelope":"from@example.com","recipient":"not-an-email","body":"hello"}`
+	request := httptest.NewRequest(http.MethodPost, "/message", strings.NewReader(body))
+	response := httptest.NewRecorder()
+	server.Handler().ServeHTTP(response, request)
+
+	if response.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid recipient, got %d", response.Code)
+	}
+}
+
+func TestHandleSubmitAcceptsValidMessageAndMarksItQueued(t *testing.T) {
+	server, enqueuer := newTestServer("")
+
+	body := `{"envelope":"from@example.com","recipient":"to@example.com","body":"hello"}`
+	request := httptest.NewRequest(http.MethodPost, "/message", strings.NewReader(body))
+	response := httptest.NewRecorder()
+	server.Handler().ServeHTTP(response, request)
+
+	if response.Code != http.StatusAccepted {
+		t.Fatalf("expected 202 for valid message, got %d: %s", response.Code, response.Body.String())
+	}
+	if len(enqueuer.enqueued) != 1 {
+		t.Fatalf("expected message to be enqueued, got %d", len(enqueuer.enqueued))
+	}
+
+	id := enqueuer.enqueued[0].Id
+	status, ok := server.statuses.getStatus(id)
+	if !ok || status != StatusQueued {
+		t.Fatalf("expected message %d to be marked queued, got %v (found=%v)", id, status, ok)
+	}
+}
+
+func TestHandleSubmitPrependsOptionalHeadersToEnqueuedBody(t *testing.T) {
+	server, enqueuer := newTestServer("")
+
+	body := `{"envelope":"from@example.com","recipient":"to@example.com","body":"hello",` +
+		`"headers":{"Subject":"Hi there","X-Mailer":"postmanq"}}`
+	request := httptest.NewRequest(http.MethodPost, "/message", strings.NewReader(body))
+	response := httptest.NewRecorder()
+	server.Handler().ServeHTTP(response, request)
+
+	if response.Code != http.StatusAccepted {
+		t.Fatalf("expected 202 for valid message with headers, got %d: %s", response.Code, response.Body.String())
+	}
+	if len(enqueuer.enqueued) != 1 {
+		t.Fatalf("expected message to be enqueued, got %d", len(enqueuer.enqueued))
+	}
+
+	expected := "Subject: Hi there\r\nX-Mailer: postmanq\r\n\r\nhello"
+	if got := enqueuer.enqueued[0].Body; got != expected {
+		t.Fatalf("expected headers prepended in sorted order, got %q, want %q", got, expected)
+	}
+}
+
+func TestHandleStatusReturnsNotFoundForUnknownId(t *testing.T) {
+	server, _ := newTestServer("")
+
+	request := httptest.NewRequest(http.MethodGet, "/message/12345", nil)
+	response := httptest.NewRecorder()
+	server.Handler().ServeHTTP(response, request)
+
+	if response.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown message id, got %d", response.Code)
+	}
+}
+
+func TestHandleStatusReturnsDeliveredAfterSetStatus(t *testing.T) {
+	server, _ := newTestServer("")
+	server.SetStatus(999, StatusDelivered)
+
+	request := httptest.NewRequest(http.MethodGet, "/message/999", nil)
+	response := httptest.NewRecorder()
+	server.Handler().ServeHTTP(response, request)
+
+	if response.Code != http.StatusOK {
+		t.Fatalf("expected 200 for known message id, got %d", response.Code)
+	}
+	if !strings.Contains(response.Body.String(), `"status":"delivered"`) {
+		t.Fatalf("expected delivered status in response body, got %s", response.Body.String())
+	}
+}
@@ -0,0 +1,67 @@
+package api
+
+import "sync"
+
+// Статус письма в очереди
+type Status int
+
+const (
+	// Письмо принято и поставлено в очередь
+	StatusQueued Status = iota
+
+	// Письмо отложено для повторной отправки
+	StatusDelayed
+
+	// Письмо доставлено
+	StatusDelivered
+
+	// При отправке письма произошла ошибка
+	StatusErrored
+)
+
+// Возвращает текстовое представление статуса
+func (this Status) String() string {
+	switch this {
+	case StatusDelayed:
+		return "delayed"
+	case StatusDelivered:
+		return "delivered"
+	case StatusErrored:
+		return "errored"
+	default:
+		return "queued"
+	}
+}
+
+// Потокобезопасное хранилище статусов писем, заполняется отправителем
+type statusStore struct {
+	mutex    sync.RWMutex
+	statuses map[int64]Status
+}
+
+// Создает новое хранилище статусов
+func newStatusStore() *statusStore {
+	this := new(statusStore)
+	this.statuses = make(map[int64]Status)
+	return this
+}
+
+// Устанавливает статус письма
+func (this *statusStore) setStatus(id int64, status Status) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.statuses[id] = status
+}
+
+// Возвращает статус письма по идентификатору
+func (this *statusStore) getStatus(id int64) (Status, bool) {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	status, ok := this.statuses[id]
+	return status, ok
+}
+
+// SetStatus позволяет отправителю сообщить о смене статуса письма
+func (this *Server) SetStatus(id int64, status Status) {
+	this.statuses.setStatus(id, status)
+}
@@ -0,0 +1,90 @@
+// Package client предоставляет Go клиента к HTTP API режима capture,
+// чтобы интеграционные тесты могли дожидаться появления писем.
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Письмо, полученное от capture API
+type Message struct {
+	Id        int64  `json:"id"`
+	Envelope  string `json:"envelope"`
+	Recipient string `json:"recipient"`
+	Body      string `json:"body"`
+}
+
+// Клиент к HTTP API режима capture
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+// Создает клиента, обращающегося к capture API по указанному адресу
+func New(baseURL string) *Client {
+	this := new(Client)
+	this.baseURL = baseURL
+	this.http = &http.Client{Timeout: 10 * time.Second}
+	return this
+}
+
+// Messages возвращает пойманные письма, отфильтрованные по получателю и ключевому слову
+func (this *Client) Messages(recipient, keyword string) ([]*Message, error) {
+	query := url.Values{}
+	if len(recipient) > 0 {
+		query.Set("recipient", recipient)
+	}
+	if len(keyword) > 0 {
+		query.Set("keyword", keyword)
+	}
+
+	response, err := this.http.Get(this.baseURL + "/messages?" + query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	var messages []*Message
+	if err := json.NewDecoder(response.Body).Decode(&messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// WaitForMessage опрашивает capture API, пока не появится письмо, подходящее
+// под получателя и ключевые слова, либо пока не истечет timeout
+func (this *Client) WaitForMessage(recipient string, keywords []string, timeout time.Duration) (*Message, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		messages, err := this.Messages(recipient, "")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, message := range messages {
+			if containsAll(message.Body, keywords) {
+				return message, nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("no message for %q with keywords %v within %s", recipient, keywords, timeout)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// containsAll проверяет, что тело письма содержит все переданные ключевые слова
+func containsAll(body string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if !strings.Contains(body, keyword) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,24 @@
+package capture
+
+import "github.com/AdOnWeb/postmanq/common"
+
+// Sender реализует режим доставки "capture": вместо установки SMTP
+// соединения письмо сохраняется в Store и считается мгновенно доставленным.
+// Переключателя в конфиге нет, и сам Sender в путь отправки не встроен:
+// его Send нужно вызывать вместо обычного SMTP клиента явно.
+type Sender struct {
+	store *Store
+}
+
+// Создает отправитель в режиме capture
+func NewSender(store *Store) *Sender {
+	this := new(Sender)
+	this.store = store
+	return this
+}
+
+// Send сохраняет письмо в хранилище вместо реальной отправки
+func (this *Sender) Send(message *common.MailMessage) error {
+	this.store.Add(message)
+	return nil
+}
@@ -0,0 +1,86 @@
+package capture
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Настройки HTTP API, отдающего пойманные письма
+type Config struct {
+	// Адрес, на котором слушает сервер capture API
+	ListenAddr string `yaml:"listenAddr"`
+}
+
+// HTTP сервер, отдающий пойманные письма
+type Server struct {
+	config Config
+	store  *Store
+	server *http.Server
+}
+
+// Создает сервер, отдающий содержимое хранилища captured писем
+func NewServer(config Config, store *Store) *Server {
+	this := new(Server)
+	this.config = config
+	this.store = store
+	return this
+}
+
+// Запускает HTTP сервер, блокируясь до остановки
+func (this *Server) Run() error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/messages", this.handleMessages)
+	mux.HandleFunc("/messages/", this.handleMessage)
+
+	this.server = &http.Server{
+		Addr:    this.config.ListenAddr,
+		Handler: mux,
+	}
+	err := this.server.ListenAndServe()
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// GET /messages?recipient=...&keyword=..., DELETE /messages
+func (this *Server) handleMessages(writer http.ResponseWriter, request *http.Request) {
+	switch request.Method {
+	case http.MethodGet:
+		recipient := request.URL.Query().Get("recipient")
+		keyword := request.URL.Query().Get("keyword")
+		writer.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(writer).Encode(this.store.Search(recipient, keyword))
+	case http.MethodDelete:
+		this.store.Clear()
+		writer.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(writer, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GET /messages/{id}
+func (this *Server) handleMessage(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		http.Error(writer, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	idString := strings.TrimPrefix(request.URL.Path, "/messages/")
+	id, err := strconv.ParseInt(idString, 10, 64)
+	if err != nil {
+		http.Error(writer, "invalid message id", http.StatusBadRequest)
+		return
+	}
+
+	message, ok := this.store.Get(id)
+	if !ok {
+		http.Error(writer, "message not found", http.StatusNotFound)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(message)
+}
@@ -0,0 +1,82 @@
+// Package capture реализует режим отправки "capture" — вместо реального SMTP
+// соединения письма складываются в память и становятся доступны через HTTP API.
+// Предназначен для интеграционных тестов, запускаемых без внешнего MTA.
+package capture
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/AdOnWeb/postmanq/common"
+)
+
+// Письмо, пойманное в режиме capture
+type CapturedMessage struct {
+	Id        int64  `json:"id"`
+	Envelope  string `json:"envelope"`
+	Recipient string `json:"recipient"`
+	Body      string `json:"body"`
+}
+
+// Хранилище пойманных писем
+type Store struct {
+	mutex    sync.RWMutex
+	messages []*CapturedMessage
+	byId     map[int64]*CapturedMessage
+}
+
+// Создает новое хранилище
+func NewStore() *Store {
+	this := new(Store)
+	this.byId = make(map[int64]*CapturedMessage)
+	return this
+}
+
+// Сохраняет письмо вместо его реальной отправки
+func (this *Store) Add(message *common.MailMessage) {
+	captured := &CapturedMessage{
+		Id:        message.Id,
+		Envelope:  message.Envelope,
+		Recipient: message.Recipient,
+		Body:      message.Body,
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.messages = append(this.messages, captured)
+	this.byId[captured.Id] = captured
+}
+
+// Возвращает письмо по идентификатору
+func (this *Store) Get(id int64) (*CapturedMessage, bool) {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	message, ok := this.byId[id]
+	return message, ok
+}
+
+// Возвращает все пойманные письма, опционально фильтруя по получателю и ключевым словам
+func (this *Store) Search(recipient, keyword string) []*CapturedMessage {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+
+	found := make([]*CapturedMessage, 0)
+	for _, message := range this.messages {
+		if len(recipient) > 0 && !strings.Contains(message.Recipient, recipient) {
+			continue
+		}
+		if len(keyword) > 0 && !strings.Contains(message.Body, keyword) {
+			continue
+		}
+		found = append(found, message)
+	}
+	return found
+}
+
+// Очищает хранилище
+func (this *Store) Clear() {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.messages = nil
+	this.byId = make(map[int64]*CapturedMessage)
+}
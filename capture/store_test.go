@@ -0,0 +1,28 @@
+package capture
+
+import (
+	"testing"
+
+	"github.com/AdOnWeb/postmanq/common"
+)
+
+func TestStoreSearch(t *testing.T) {
+	store := NewStore()
+	store.Add(&common.MailMessage{Id: 1, Envelope: "from@example.com", Recipient: "to@example.com", Body: "hello world"})
+	store.Add(&common.MailMessage{Id: 2, Envelope: "from@example.com", Recipient: "other@example.com", Body: "goodbye"})
+
+	found := store.Search("to@example.com", "")
+	if len(found) != 1 || found[0].Id != 1 {
+		t.Fatalf("expected to find message 1 by recipient, got %+v", found)
+	}
+
+	found = store.Search("", "goodbye")
+	if len(found) != 1 || found[0].Id != 2 {
+		t.Fatalf("expected to find message 2 by keyword, got %+v", found)
+	}
+
+	store.Clear()
+	if len(store.Search("", "")) != 0 {
+		t.Fatalf("expected store to be empty after Clear")
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+
+	"github.com/AdOnWeb/postmanq/dkim"
+)
+
+func main() {
+	var selector, domain, keyFile string
+	var bits int
+	flag.StringVar(&selector, "selector", "", "DKIM selector, e.g. \"s1\"")
+	flag.StringVar(&domain, "domain", "", "signing domain, e.g. \"example.com\"")
+	flag.StringVar(&keyFile, "out", "dkim.private.pem", "file to write the generated private key to")
+	flag.IntVar(&bits, "bits", 2048, "RSA key size in bits")
+	flag.Parse()
+
+	if len(selector) == 0 || len(domain) == 0 {
+		flag.PrintDefaults()
+		log.Fatal("both -selector and -domain are required")
+	}
+
+	key, err := dkim.GenerateKeyPair(bits)
+	if err != nil {
+		log.Fatalf("failed to generate key: %s", err)
+	}
+
+	if err := ioutil.WriteFile(keyFile, []byte(dkim.EncodePrivateKeyPEM(key)), 0600); err != nil {
+		log.Fatalf("failed to write private key to %s: %s", keyFile, err)
+	}
+
+	record, err := dkim.DNSTXTRecord(key)
+	if err != nil {
+		log.Fatalf("failed to build DNS TXT record: %s", err)
+	}
+
+	fmt.Printf("private key written to %s\n", keyFile)
+	fmt.Printf("publish this DNS TXT record at %s._domainkey.%s:\n%s\n", selector, domain, record)
+}
@@ -70,6 +70,9 @@ const (
 	SixHoursDelayedBinding
 	DayDelayedBinding
 	NotSendDelayedBinding
+
+	// Очередь дайджеста, письма в ней не отправляются сразу, а копятся и объединяются в одно письмо
+	DigestDelayedBinding
 )
 
 // Ошибка во время отпрвки письма
@@ -107,6 +110,9 @@ type MailMessage struct {
 	// Тип очереди, в которою письмо уже было отправлено после неудачной отправки, ипользуется для цепочки очередей
 	BindingType DelayedBindingType `json:"bindingType"`
 
+	// Количество уже сделанных попыток отправки, используется политикой повторов
+	AttemptCount int `json:"attemptCount"`
+
 	// Ошибка отправки
 	Error *MailError `json:"error"`
 }
@@ -124,6 +130,12 @@ func (this *MailMessage) Init() {
 	}
 }
 
+// VERPEnvelope возвращает обратный адрес с VERP меткой этого письма, его нужно
+// использовать в MAIL FROM вместо Envelope, чтобы bounce можно было сопоставить с письмом
+func (this *MailMessage) VERPEnvelope() (string, error) {
+	return EncodeVERPEnvelope(this.Envelope, this.Id)
+}
+
 // Получает домен из адреса
 func (this *MailMessage) getHostnameFromEmail(email string) (string, error) {
 	matches := EmailRegexp.FindAllStringSubmatch(email, -1)
@@ -160,6 +172,13 @@ func ReturnMail(event *SendEvent, err error) {
 		}
 	}
 
+	// письмо с ошибкой отправки классифицируется и получает тип очереди
+	// согласно текущей политике повторов, письмо без ошибки просто откладывается
+	if event.Message.Error != nil {
+		event.Message.AttemptCount++
+		event.Message.BindingType = currentPolicy().bind(event.Message.Error.Code, event.Message.AttemptCount)
+	}
+
 	// отпускаем поток получателя сообщений из очереди
 	if event.Message.Error == nil {
 		event.Result <- DelaySendEventResult
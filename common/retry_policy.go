@@ -0,0 +1,241 @@
+package common
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+)
+
+// Класс ошибки SMTP, к которому применяется отдельное правило повтора
+type ErrorClass int
+
+const (
+	// Код ошибки не распознан ни одним правилом
+	UnknownErrorClass ErrorClass = iota
+
+	// Временная ошибка 4xx, кроме greylisting и квоты
+	TransientErrorClass
+
+	// Постоянная ошибка 5xx, кроме policy reject
+	PermanentErrorClass
+
+	// Greylisting: 421, 450, 451
+	GreylistErrorClass
+
+	// Превышена квота получателя: 452
+	QuotaErrorClass
+
+	// Отказ по политике почтовика: 550, 553
+	PolicyErrorClass
+
+	// Ошибка DNS или таймаут соединения, код недоступен
+	TimeoutErrorClass
+)
+
+// Правило повтора для одного класса ошибок
+type RetryRule struct {
+	// В какую отложенную очередь отправлять письмо при этом классе ошибки.
+	// UnknownDelayedBinding (значение по умолчанию) означает "использовать
+	// стандартную лесенку очередей", см. standardRetryLadder
+	Binding DelayedBindingType `yaml:"binding"`
+
+	// Сколько попыток отправки разрешено для этого класса ошибки: попытки с
+	// attemptCount от 1 до MaxTryCount включительно еще отправляются повторно,
+	// начиная с attemptCount == MaxTryCount+1 письмо перестает отправляться
+	MaxTryCount int `yaml:"maxTryCount"`
+
+	// Разброс, на который можно дополнительно задержать письмо этого класса, в секундах
+	JitterSeconds int `yaml:"jitterSeconds"`
+}
+
+// RetryPolicy сопоставляет классы ошибок SMTP с правилами повтора отправки
+type RetryPolicy struct {
+	Transient RetryRule `yaml:"transient"`
+	Permanent RetryRule `yaml:"permanent"`
+	Greylist  RetryRule `yaml:"greylist"`
+	Quota     RetryRule `yaml:"quota"`
+	Policy    RetryRule `yaml:"policy"`
+	Timeout   RetryRule `yaml:"timeout"`
+	Unknown   RetryRule `yaml:"unknown"`
+
+	metrics retryMetrics
+}
+
+// standardRetryLadder - прежняя жесткая лесенка очередей postmanq: каждая следующая
+// попытка отправки откладывает письмо на следующий, более долгий срок, пока письмо
+// либо не уйдет, либо лесенка не закончится и письмо не перестанет отправляться
+var standardRetryLadder = []DelayedBindingType{
+	SecondDelayedBinding,
+	ThirtySecondDelayedBinding,
+	MinuteDelayedBinding,
+	FiveMinutesDelayedBinding,
+	TenMinutesDelayedBinding,
+	TwentyMinutesDelayedBinding,
+	ThirtyMinutesDelayedBinding,
+	FortyMinutesDelayedBinding,
+	FiftyMinutesDelayedBinding,
+	HourDelayedBinding,
+	SixHoursDelayedBinding,
+	DayDelayedBinding,
+}
+
+// retryPolicyMutex защищает currentRetryPolicy от гонки между SetRetryPolicy,
+// вызываемым при (пере)загрузке конфига, и bind/RetryMetrics, читающими политику
+// из каждого работающего воркера отправки
+var retryPolicyMutex sync.RWMutex
+
+// Действующая политика повторов, по умолчанию - прежняя жесткая лесенка очередей
+var currentRetryPolicy = DefaultRetryPolicy()
+
+// DefaultRetryPolicy возвращает политику, повторяющую прежнее поведение postmanq:
+// transient, timeout и нераспознанные ошибки проходят по standardRetryLadder.
+// Greylisting повторяется через FiveMinutesDelayedBinding, квота - через
+// HourDelayedBinding, оба до MaxTryConnectionCount попыток; постоянные 5xx и
+// policy reject завершаются сразу после первой неудачи
+func DefaultRetryPolicy() *RetryPolicy {
+	ladder := RetryRule{Binding: UnknownDelayedBinding, MaxTryCount: len(standardRetryLadder)}
+	return &RetryPolicy{
+		Transient: ladder,
+		Permanent: RetryRule{Binding: NotSendDelayedBinding, MaxTryCount: 1},
+		Greylist:  RetryRule{Binding: FiveMinutesDelayedBinding, MaxTryCount: MaxTryConnectionCount, JitterSeconds: 60},
+		Quota:     RetryRule{Binding: HourDelayedBinding, MaxTryCount: MaxTryConnectionCount},
+		Policy:    RetryRule{Binding: NotSendDelayedBinding, MaxTryCount: 1},
+		Timeout:   ladder,
+		Unknown:   ladder,
+	}
+}
+
+// SetRetryPolicy заменяет действующую политику повторов, вызывается при загрузке
+// конфига. Безопасно вызывать, пока воркеры отправки уже работают: ReturnMail
+// берет текущую политику через currentPolicy под RLock на каждый возврат письма
+func SetRetryPolicy(policy *RetryPolicy) {
+	retryPolicyMutex.Lock()
+	defer retryPolicyMutex.Unlock()
+	currentRetryPolicy = policy
+}
+
+// currentPolicy возвращает действующую политику повторов, безопасно для
+// конкурентного вызова из воркеров отправки и из перезагрузки конфига
+func currentPolicy() *RetryPolicy {
+	retryPolicyMutex.RLock()
+	defer retryPolicyMutex.RUnlock()
+	return currentRetryPolicy
+}
+
+// ClassifyErrorCode определяет класс ошибки по SMTP коду ответа
+func ClassifyErrorCode(code int) ErrorClass {
+	switch code {
+	case 421, 450, 451:
+		return GreylistErrorClass
+	case 452:
+		return QuotaErrorClass
+	case 550, 553:
+		return PolicyErrorClass
+	case 0:
+		return TimeoutErrorClass
+	}
+
+	switch {
+	case code >= 400 && code < 500:
+		return TransientErrorClass
+	case code >= 500 && code < 600:
+		return PermanentErrorClass
+	default:
+		return UnknownErrorClass
+	}
+}
+
+// rule возвращает правило повтора для класса ошибки
+func (this *RetryPolicy) rule(class ErrorClass) RetryRule {
+	switch class {
+	case GreylistErrorClass:
+		return this.Greylist
+	case QuotaErrorClass:
+		return this.Quota
+	case PolicyErrorClass:
+		return this.Policy
+	case TransientErrorClass:
+		return this.Transient
+	case PermanentErrorClass:
+		return this.Permanent
+	case TimeoutErrorClass:
+		return this.Timeout
+	default:
+		return this.Unknown
+	}
+}
+
+// bind классифицирует код ошибки и возвращает тип очереди, в которую нужно вернуть письмо
+// с учетом уже сделанных попыток. Попутно увеличивает счетчик метрик по классу ошибки
+func (this *RetryPolicy) bind(code int, attemptCount int) DelayedBindingType {
+	class := ClassifyErrorCode(code)
+	this.metrics.increment(class)
+
+	rule := this.rule(class)
+	if rule.MaxTryCount > 0 && attemptCount > rule.MaxTryCount {
+		return NotSendDelayedBinding
+	}
+
+	if rule.Binding == UnknownDelayedBinding {
+		// attemptCount считается с 1, лесенка индексируется с 0: первая неудачная
+		// попытка откладывает письмо на standardRetryLadder[0], а не на [1]
+		index := attemptCount - 1
+		if index < 0 {
+			index = 0
+		}
+		if index >= len(standardRetryLadder) {
+			return NotSendDelayedBinding
+		}
+		return standardRetryLadder[index]
+	}
+	return rule.Binding
+}
+
+// JitterDuration возвращает случайную задержку в пределах JitterSeconds для правила этого класса
+func (this *RetryPolicy) JitterDuration(class ErrorClass) int {
+	rule := this.rule(class)
+	if rule.JitterSeconds <= 0 {
+		return 0
+	}
+	return rand.Intn(rule.JitterSeconds + 1)
+}
+
+// retryMetrics считает количество попаданий в каждый класс ошибок, чтобы были видны retry storm
+type retryMetrics struct {
+	mutex   sync.Mutex
+	counts  map[ErrorClass]*int64
+	initRun sync.Once
+}
+
+func (this *retryMetrics) increment(class ErrorClass) {
+	this.initRun.Do(func() {
+		this.mutex.Lock()
+		defer this.mutex.Unlock()
+		this.counts = make(map[ErrorClass]*int64)
+	})
+
+	this.mutex.Lock()
+	counter, ok := this.counts[class]
+	if !ok {
+		var zero int64
+		counter = &zero
+		this.counts[class] = counter
+	}
+	this.mutex.Unlock()
+
+	atomic.AddInt64(counter, 1)
+}
+
+// RetryMetrics возвращает снимок количества повторов по каждому классу ошибок
+func RetryMetrics() map[ErrorClass]int64 {
+	policy := currentPolicy()
+
+	policy.metrics.mutex.Lock()
+	defer policy.metrics.mutex.Unlock()
+
+	snapshot := make(map[ErrorClass]int64, len(policy.metrics.counts))
+	for class, counter := range policy.metrics.counts {
+		snapshot[class] = atomic.LoadInt64(counter)
+	}
+	return snapshot
+}
@@ -0,0 +1,87 @@
+package common
+
+import "testing"
+
+func TestClassifyErrorCode(t *testing.T) {
+	cases := map[int]ErrorClass{
+		451: GreylistErrorClass,
+		450: GreylistErrorClass,
+		421: GreylistErrorClass,
+		452: QuotaErrorClass,
+		550: PolicyErrorClass,
+		553: PolicyErrorClass,
+		432: TransientErrorClass,
+		511: PermanentErrorClass,
+		0:   TimeoutErrorClass,
+		999: UnknownErrorClass,
+	}
+
+	for code, expected := range cases {
+		if actual := ClassifyErrorCode(code); actual != expected {
+			t.Errorf("ClassifyErrorCode(%d) = %v, want %v", code, actual, expected)
+		}
+	}
+}
+
+func TestRetryPolicyBindStopsAfterMaxTryCount(t *testing.T) {
+	policy := &RetryPolicy{
+		Greylist: RetryRule{Binding: FiveMinutesDelayedBinding, MaxTryCount: 2},
+	}
+
+	// MaxTryCount - это количество разрешенных попыток, а не количество повторов:
+	// с MaxTryCount: 2 обе попытки 1 и 2 еще должны уходить на повтор, и только
+	// попытка 3, которая эту квоту превышает, останавливает отправку
+	if binding := policy.bind(451, 1); binding != FiveMinutesDelayedBinding {
+		t.Fatalf("expected first attempt to requeue to FiveMinutesDelayedBinding, got %v", binding)
+	}
+	if binding := policy.bind(451, 2); binding != FiveMinutesDelayedBinding {
+		t.Fatalf("expected attempt still within MaxTryCount to requeue to FiveMinutesDelayedBinding, got %v", binding)
+	}
+	if binding := policy.bind(451, 3); binding != NotSendDelayedBinding {
+		t.Fatalf("expected attempt exceeding MaxTryCount to stop retrying, got %v", binding)
+	}
+}
+
+func TestDefaultRetryPolicyWalksStandardLadderForTransientErrors(t *testing.T) {
+	policy := DefaultRetryPolicy()
+
+	for attempt, expected := range standardRetryLadder {
+		// attemptCount считается с 1, standardRetryLadder индексируется с 0
+		if binding := policy.bind(432, attempt+1); binding != expected {
+			t.Fatalf("attempt %d: expected %v, got %v", attempt+1, expected, binding)
+		}
+	}
+
+	// после того, как лесенка закончилась, письмо перестает отправляться
+	if binding := policy.bind(432, len(standardRetryLadder)+1); binding != NotSendDelayedBinding {
+		t.Fatalf("expected NotSendDelayedBinding once the ladder is exhausted, got %v", binding)
+	}
+}
+
+func TestDefaultRetryPolicyStopsImmediatelyOnPermanentAndPolicyErrors(t *testing.T) {
+	policy := DefaultRetryPolicy()
+
+	if binding := policy.bind(511, 1); binding != NotSendDelayedBinding {
+		t.Fatalf("expected permanent 5xx errors to stop immediately, got %v", binding)
+	}
+	if binding := policy.bind(550, 1); binding != NotSendDelayedBinding {
+		t.Fatalf("expected 550 policy reject to stop immediately, got %v", binding)
+	}
+}
+
+func TestSetRetryPolicyIsSafeForConcurrentUse(t *testing.T) {
+	defer SetRetryPolicy(DefaultRetryPolicy())
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			SetRetryPolicy(DefaultRetryPolicy())
+		}
+		close(done)
+	}()
+
+	for i := 0; i < 100; i++ {
+		currentPolicy().bind(432, 1)
+	}
+	<-done
+}
@@ -0,0 +1,40 @@
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Разделитель, отличающий VERP метку от локальной части адреса
+const verpSeparator = "+"
+
+// EncodeVERPEnvelope добавляет к обратному адресу VERP метку с идентификатором письма,
+// чтобы по ответившему на это письмо bounce можно было определить, какое письмо не дошло
+func EncodeVERPEnvelope(envelope string, messageId int64) (string, error) {
+	at := strings.LastIndex(envelope, "@")
+	if at == -1 {
+		return "", fmt.Errorf("invalid envelope address %q", envelope)
+	}
+
+	localPart := envelope[:at]
+	domain := envelope[at:]
+	return localPart + verpSeparator + strconv.FormatInt(messageId, 10) + domain, nil
+}
+
+// DecodeVERPEnvelope достает идентификатор письма из обратного адреса,
+// в который он был добавлен EncodeVERPEnvelope
+func DecodeVERPEnvelope(address string) (int64, error) {
+	at := strings.LastIndex(address, "@")
+	if at == -1 {
+		return 0, fmt.Errorf("invalid address %q", address)
+	}
+
+	localPart := address[:at]
+	plus := strings.LastIndex(localPart, verpSeparator)
+	if plus == -1 {
+		return 0, fmt.Errorf("address %q has no VERP tag", address)
+	}
+
+	return strconv.ParseInt(localPart[plus+len(verpSeparator):], 10, 64)
+}
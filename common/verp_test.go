@@ -0,0 +1,29 @@
+package common
+
+import "testing"
+
+func TestVERPEnvelopeRoundTrip(t *testing.T) {
+	encoded, err := EncodeVERPEnvelope("bounces@example.com", 123456789)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := "bounces+123456789@example.com"
+	if encoded != expected {
+		t.Fatalf("expected %q, got %q", expected, encoded)
+	}
+
+	id, err := DecodeVERPEnvelope(encoded)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if id != 123456789 {
+		t.Fatalf("expected id 123456789, got %d", id)
+	}
+}
+
+func TestDecodeVERPEnvelopeWithoutTag(t *testing.T) {
+	if _, err := DecodeVERPEnvelope("plain@example.com"); err == nil {
+		t.Fatal("expected error for address without VERP tag")
+	}
+}
@@ -0,0 +1,161 @@
+package digest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/AdOnWeb/postmanq/common"
+)
+
+// Publisher отдает собранное письмо дайджеста обратно в общий конвейер отправки,
+// чтобы на него продолжали действовать ReturnMail и цепочка отложенных очередей
+type Publisher interface {
+	Publish(message *common.MailMessage)
+}
+
+// Aggregator копит письма по получателю и периодически объединяет их в одно письмо
+type Aggregator struct {
+	config    Config
+	template  *template.Template
+	optOut    OptOutChecker
+	publisher Publisher
+
+	mutex   sync.Mutex
+	pending map[string][]pendingItem
+
+	stop chan struct{}
+}
+
+// NewAggregator создает агрегатор дайджеста. optOut может быть nil, тогда письма
+// получателей никогда не исключаются
+func NewAggregator(config Config, optOut OptOutChecker, publisher Publisher) (*Aggregator, error) {
+	config.Init()
+
+	tpl, err := parseTemplate(config.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	this := new(Aggregator)
+	this.config = config
+	this.template = tpl
+	this.optOut = optOut
+	this.publisher = publisher
+	this.pending = make(map[string][]pendingItem)
+	this.stop = make(chan struct{})
+
+	if err := this.load(); err != nil {
+		return nil, err
+	}
+
+	return this, nil
+}
+
+// Add кладет письмо в дайджест получателя вместо немедленной отправки
+func (this *Aggregator) Add(message *common.MailMessage) error {
+	if this.optOut != nil && this.optOut(message.Recipient) {
+		return nil
+	}
+
+	this.mutex.Lock()
+	this.pending[message.Recipient] = append(this.pending[message.Recipient], pendingItemFromMessage(message))
+	this.mutex.Unlock()
+
+	return this.persist()
+}
+
+// Run запускает периодическую сборку и отправку накопленных писем,
+// блокируется до вызова Stop
+func (this *Aggregator) Run() {
+	ticker := time.NewTicker(this.config.Window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			this.Flush()
+		case <-this.stop:
+			return
+		}
+	}
+}
+
+// Stop останавливает периодическую сборку
+func (this *Aggregator) Stop() {
+	close(this.stop)
+}
+
+// Flush немедленно собирает и отправляет дайджест по всем получателям
+func (this *Aggregator) Flush() {
+	this.mutex.Lock()
+	pending := this.pending
+	this.pending = make(map[string][]pendingItem)
+	this.mutex.Unlock()
+
+	for recipient, items := range pending {
+		if len(items) == 0 {
+			continue
+		}
+
+		body, err := this.render(items)
+		if err != nil {
+			// не теряем письма при ошибке рендеринга, вернем их в следующий раз
+			this.mutex.Lock()
+			this.pending[recipient] = append(items, this.pending[recipient]...)
+			this.mutex.Unlock()
+			continue
+		}
+
+		message := &common.MailMessage{
+			Envelope:    items[0].Envelope,
+			Recipient:   recipient,
+			Body:        body,
+			BindingType: common.DigestDelayedBinding,
+		}
+		message.Init()
+		this.publisher.Publish(message)
+	}
+
+	this.persist()
+}
+
+// render собирает тело итогового письма по шаблону
+func (this *Aggregator) render(items []pendingItem) (string, error) {
+	var buffer bytes.Buffer
+	if err := this.template.Execute(&buffer, struct{ Messages []pendingItem }{Messages: items}); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+// persist сохраняет несобранные письма на диск, чтобы пережить перезапуск
+func (this *Aggregator) persist() error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	data, err := json.Marshal(this.pending)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(this.config.PersistFile, data, 0644)
+}
+
+// load восстанавливает несобранные письма после перезапуска
+func (this *Aggregator) load() error {
+	data, err := ioutil.ReadFile(this.config.PersistFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return json.Unmarshal(data, &this.pending)
+}
@@ -0,0 +1,58 @@
+package digest
+
+import (
+	"os"
+	"testing"
+
+	"github.com/AdOnWeb/postmanq/common"
+)
+
+type fakePublisher struct {
+	published []*common.MailMessage
+}
+
+func (this *fakePublisher) Publish(message *common.MailMessage) {
+	this.published = append(this.published, message)
+}
+
+func TestAggregatorFlushGroupsByRecipientAndRespectsOptOut(t *testing.T) {
+	persistFile := "test_digest.state"
+	defer os.Remove(persistFile)
+
+	optOut := func(recipient string) bool { return recipient == "blocked@example.com" }
+	publisher := new(fakePublisher)
+
+	aggregator, err := NewAggregator(Config{PersistFile: persistFile, Template: "{{range .Messages}}{{.Body}};{{end}}"}, optOut, publisher)
+	if err != nil {
+		t.Fatalf("unexpected error creating aggregator: %s", err)
+	}
+
+	messages := []*common.MailMessage{
+		{Envelope: "from@example.com", Recipient: "to@example.com", Body: "one"},
+		{Envelope: "from@example.com", Recipient: "to@example.com", Body: "two"},
+		{Envelope: "from@example.com", Recipient: "blocked@example.com", Body: "three"},
+	}
+	for _, message := range messages {
+		message.Init()
+		if err := aggregator.Add(message); err != nil {
+			t.Fatalf("unexpected error adding message: %s", err)
+		}
+	}
+
+	aggregator.Flush()
+
+	if len(publisher.published) != 1 {
+		t.Fatalf("expected exactly one digest to be published, got %d", len(publisher.published))
+	}
+
+	published := publisher.published[0]
+	if published.Recipient != "to@example.com" {
+		t.Fatalf("expected digest for to@example.com, got %s", published.Recipient)
+	}
+	if published.Body != "one;two;" {
+		t.Fatalf("unexpected digest body: %s", published.Body)
+	}
+	if published.BindingType != common.DigestDelayedBinding {
+		t.Fatalf("expected DigestDelayedBinding, got %v", published.BindingType)
+	}
+}
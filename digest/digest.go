@@ -0,0 +1,60 @@
+// Package digest реализует очередь дайджеста: вместо немедленной отправки
+// письма группируются по получателю и через заданное окно времени
+// объединяются в одно письмо по шаблону.
+package digest
+
+import (
+	"text/template"
+	"time"
+
+	"github.com/AdOnWeb/postmanq/common"
+)
+
+// Настройки дайджест очереди
+type Config struct {
+	// Как часто собирать и отправлять накопленные письма, например "1h" или "24h"
+	Window time.Duration `yaml:"window"`
+
+	// Путь к файлу, в котором хранятся еще не отправленные письма между перезапусками
+	PersistFile string `yaml:"persistFile"`
+
+	// Шаблон итогового письма, в него передается срез накопленных писем под именем Messages,
+	// у каждого элемента есть поля Envelope, Recipient, Body и CreatedAt (см. pendingItem)
+	Template string `yaml:"template"`
+}
+
+// Init задает значения по умолчанию
+func (this *Config) Init() {
+	if this.Window == 0 {
+		this.Window = time.Hour
+	}
+	if len(this.PersistFile) == 0 {
+		this.PersistFile = "digest.state"
+	}
+}
+
+// OptOutChecker решает, исключен ли получатель из дайджеста
+// Реализация подключается пользователем, например через запрос к биллингу
+type OptOutChecker func(recipient string) bool
+
+// parseTemplate компилирует шаблон письма один раз при создании агрегатора
+func parseTemplate(text string) (*template.Template, error) {
+	return template.New("digest").Parse(text)
+}
+
+// pendingItem - письмо, ожидающее попадания в дайджест очередного получателя
+type pendingItem struct {
+	Envelope  string    `json:"envelope"`
+	Recipient string    `json:"recipient"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func pendingItemFromMessage(message *common.MailMessage) pendingItem {
+	return pendingItem{
+		Envelope:  message.Envelope,
+		Recipient: message.Recipient,
+		Body:      message.Body,
+		CreatedAt: message.CreatedDate,
+	}
+}
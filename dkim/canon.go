@@ -0,0 +1,44 @@
+package dkim
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	runsOfSpacesAndTabs = regexp.MustCompile(`[ \t]+`)
+	runsOfWhitespace    = regexp.MustCompile(`\s+`)
+)
+
+// canonicalizeBody канонизирует тело письма перед хешированием
+func canonicalizeBody(body string, c Canonicalization) string {
+	if c == RelaxedCanonicalization {
+		body = runsOfSpacesAndTabs.ReplaceAllString(body, " ")
+		lines := strings.Split(body, "\r\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimRight(line, " \t")
+		}
+		body = strings.Join(lines, "\r\n")
+	}
+
+	// оба варианта канонализации требуют ровно один завершающий CRLF
+	body = strings.TrimRight(body, "\r\n") + "\r\n"
+	return body
+}
+
+// canonicalizeHeader канонизирует заголовок. rawLine - исходная строка "Name: value"
+// ровно такой, какой она пришла в письме; simple канонализация обязана хешировать
+// именно эти байты, не переформатируя их
+func canonicalizeHeader(rawLine, name, value string, c Canonicalization) string {
+	if c == SimpleCanonicalization {
+		if len(rawLine) == 0 {
+			// овер-подписанный заголовок, которого еще нет в письме - хешируется как пустая строка
+			return ""
+		}
+		return rawLine
+	}
+
+	name = strings.ToLower(strings.TrimSpace(name))
+	value = runsOfWhitespace.ReplaceAllString(strings.TrimSpace(value), " ")
+	return name + ":" + value
+}
@@ -0,0 +1,81 @@
+// Package dkim подписывает исходящие письма DKIM подписью перед отправкой
+// и проверяет DKIM подпись входящих bounce писем, прежде чем доверять им
+// в рамках feedback loop.
+package dkim
+
+import "time"
+
+// Канонализация заголовков и тела письма при подписи
+type Canonicalization string
+
+const (
+	SimpleCanonicalization  Canonicalization = "simple"
+	RelaxedCanonicalization Canonicalization = "relaxed"
+)
+
+// Profile - настройки подписи для одного домена отправителя (HostnameFrom)
+type Profile struct {
+	// Домен, для которого действует этот профиль, сравнивается с MailMessage.HostnameFrom
+	Domain string `yaml:"domain"`
+
+	// Текущие и предыдущие селекторы, первый в списке - активный на подпись,
+	// остальные остаются только для проверки еще не истекших подписей во время
+	// ротации. Чтобы повернуть селектор, оператор добавляет новый в начало списка;
+	// ротация никогда не должна сама возвращаться к уже отозванным селекторам,
+	// для которых могла быть удалена DNS TXT запись
+	Selectors []string `yaml:"selectors"`
+
+	// Как часто рекомендуется поворачивать активный селектор, например "720h" для
+	// ежемесячной ротации. Используется только для напоминания операторам через
+	// RotationOverdue, сам выбор активного селектора всегда берет Selectors[0]
+	RotateEvery time.Duration `yaml:"rotateEvery"`
+
+	// Когда был добавлен текущий Selectors[0], нужно для RotationOverdue
+	SelectorSince time.Time `yaml:"selectorSince"`
+
+	// Пути к файлам с приватными ключами в формате PEM, по одному на каждый селектор
+	// из Selectors; так при ротации каждый селектор подписывает своим собственным ключом
+	PrivateKeyFiles map[string]string `yaml:"privateKeyFiles"`
+
+	// Заголовки, которые нужно подписать, в порядке появления в письме
+	HeadersToSign []string `yaml:"headersToSign"`
+
+	// Заголовки, которые нужно овер-подписать: их имя попадает в h= даже если
+	// заголовка еще нет в письме, это защищает от добавления такого заголовка позже
+	OversignHeaders []string `yaml:"oversignHeaders"`
+
+	// Канонализация заголовков и тела, по умолчанию relaxed/relaxed
+	Canonicalization Canonicalization `yaml:"canonicalization"`
+}
+
+// Init задает значения по умолчанию для профиля
+func (this *Profile) Init() {
+	if len(this.Canonicalization) == 0 {
+		this.Canonicalization = RelaxedCanonicalization
+	}
+	if len(this.HeadersToSign) == 0 {
+		this.HeadersToSign = []string{"From", "To", "Subject", "Date", "Message-Id"}
+	}
+}
+
+// activeSelector возвращает селектор, которым сейчас нужно подписывать письма.
+// Им всегда является Selectors[0]: остальные элементы списка - это уже отозванные
+// селекторы, оставленные только чтобы Verify мог проверить еще не истекшие старые
+// подписи. Подпись никогда не должна циклически возвращаться к ним, иначе письмо
+// будет подписано селектором, чья DNS TXT запись может быть уже удалена
+func (this *Profile) activeSelector() string {
+	if len(this.Selectors) == 0 {
+		return ""
+	}
+	return this.Selectors[0]
+}
+
+// RotationOverdue сообщает, что активный селектор используется дольше RotateEvery
+// и оператору пора добавить новый селектор в начало Selectors. Сама ротация -
+// ручное действие оператора, эта функция лишь помогает не забыть ее сделать
+func (this *Profile) RotationOverdue(now time.Time) bool {
+	if this.RotateEvery <= 0 || this.SelectorSince.IsZero() {
+		return false
+	}
+	return now.Sub(this.SelectorSince) >= this.RotateEvery
+}
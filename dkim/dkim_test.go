@@ -0,0 +1,92 @@
+package dkim
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCanonicalizeBodyRelaxed(t *testing.T) {
+	body := "line one  \r\nline two\t\r\n\r\n\r\n"
+	expected := "line one\r\nline two\r\n"
+	if actual := canonicalizeBody(body, RelaxedCanonicalization); actual != expected {
+		t.Fatalf("expected %q, got %q", expected, actual)
+	}
+}
+
+func TestProfileActiveSelectorNeverCyclesBackToRetiredSelectors(t *testing.T) {
+	profile := &Profile{
+		Selectors:     []string{"s2", "s1"},
+		RotateEvery:   time.Hour,
+		SelectorSince: time.Unix(0, 0),
+	}
+
+	// SelectorSince - начало эпохи, так что ротация давно просрочена; выбор
+	// селектора не зависит от времени, и при повторных вызовах активным
+	// остается только Selectors[0]
+	if selector := profile.activeSelector(); selector != "s2" {
+		t.Fatalf("expected s2 to remain active although rotation is overdue, got %q", selector)
+	}
+	if selector := profile.activeSelector(); selector != "s2" {
+		t.Fatalf("expected s2 to remain active, rotation must never cycle back to s1, got %q", selector)
+	}
+}
+
+func TestProfileRotationOverdue(t *testing.T) {
+	profile := &Profile{
+		Selectors:     []string{"s1"},
+		RotateEvery:   time.Hour,
+		SelectorSince: time.Unix(0, 0),
+	}
+
+	if profile.RotationOverdue(time.Unix(0, 0).Add(30 * time.Minute)) {
+		t.Fatal("expected rotation not to be overdue before RotateEvery has elapsed")
+	}
+	if !profile.RotationOverdue(time.Unix(0, 0).Add(2 * time.Hour)) {
+		t.Fatal("expected rotation to be overdue after RotateEvery has elapsed")
+	}
+}
+
+func TestGenerateKeyPairAndDNSRecord(t *testing.T) {
+	key, err := GenerateKeyPair(1024)
+	if err != nil {
+		t.Fatalf("unexpected error generating key: %s", err)
+	}
+
+	record, err := DNSTXTRecord(key)
+	if err != nil {
+		t.Fatalf("unexpected error building DNS record: %s", err)
+	}
+	if len(record) == 0 {
+		t.Fatal("expected non-empty DNS TXT record")
+	}
+}
+
+func TestSplitCanonTag(t *testing.T) {
+	if header, body := splitCanonTag(""); header != SimpleCanonicalization || body != SimpleCanonicalization {
+		t.Fatalf("expected simple/simple default, got %v/%v", header, body)
+	}
+	if header, body := splitCanonTag("relaxed/simple"); header != RelaxedCanonicalization || body != SimpleCanonicalization {
+		t.Fatalf("expected relaxed/simple, got %v/%v", header, body)
+	}
+	if header, body := splitCanonTag("relaxed"); header != RelaxedCanonicalization || body != SimpleCanonicalization {
+		t.Fatalf("expected relaxed header with simple body default, got %v/%v", header, body)
+	}
+}
+
+func TestFindHeaderPreservesRawBytesForSimpleCanonicalization(t *testing.T) {
+	headers := []string{"Subject:  Hello   there"}
+	rawLine, value := findHeader(headers, "Subject")
+	if rawLine != headers[0] {
+		t.Fatalf("expected raw header line to be preserved, got %q", rawLine)
+	}
+
+	simple := canonicalizeHeader(rawLine, "Subject", value, SimpleCanonicalization)
+	if simple != headers[0] {
+		t.Fatalf("expected simple canonicalization to preserve original bytes, got %q", simple)
+	}
+
+	relaxed := canonicalizeHeader(rawLine, "Subject", value, RelaxedCanonicalization)
+	if relaxed != "subject:Hello there" {
+		t.Fatalf("unexpected relaxed canonicalization: %q", relaxed)
+	}
+}
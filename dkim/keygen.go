@@ -0,0 +1,34 @@
+package dkim
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+)
+
+// GenerateKeyPair создает новую пару ключей RSA для DKIM селектора
+func GenerateKeyPair(bits int) (*rsa.PrivateKey, error) {
+	if bits == 0 {
+		bits = 2048
+	}
+	return rsa.GenerateKey(rand.Reader, bits)
+}
+
+// EncodePrivateKeyPEM кодирует приватный ключ в PEM, пригодный для PrivateKeyFiles профиля
+func EncodePrivateKeyPEM(key *rsa.PrivateKey) string {
+	block := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}
+	return string(pem.EncodeToMemory(block))
+}
+
+// DNSTXTRecord формирует DNS TXT запись, которую нужно опубликовать на
+// selector._domainkey.domain, чтобы Verify мог найти публичный ключ
+func DNSTXTRecord(key *rsa.PrivateKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("v=DKIM1; k=rsa; p=%s", base64.StdEncoding.EncodeToString(der)), nil
+}
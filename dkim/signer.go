@@ -0,0 +1,194 @@
+package dkim
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/AdOnWeb/postmanq/common"
+)
+
+// Signer подписывает исходящие письма DKIM подписью согласно профилю их домена
+// отправителя. Sign ожидает финальное тело письма с заголовками; сам Signer
+// в путь отправки (фазу DATA) не встроен, Sign нужно вызвать явно
+type Signer struct {
+	profiles map[string]*loadedProfile
+}
+
+// loadedProfile - профиль с уже разобранными приватными ключами всех его селекторов
+type loadedProfile struct {
+	profile *Profile
+	keys    map[string]*rsa.PrivateKey
+}
+
+// NewSigner загружает приватные ключи всех профилей и готовит подписывающий объект
+func NewSigner(profiles []*Profile) (*Signer, error) {
+	this := new(Signer)
+	this.profiles = make(map[string]*loadedProfile)
+
+	for _, profile := range profiles {
+		profile.Init()
+
+		loaded := &loadedProfile{profile: profile, keys: map[string]*rsa.PrivateKey{}}
+		for _, selector := range profile.Selectors {
+			path, ok := profile.PrivateKeyFiles[selector]
+			if !ok {
+				return nil, fmt.Errorf("dkim: domain %q: no privateKeyFiles entry for selector %q", profile.Domain, selector)
+			}
+
+			key, err := loadPrivateKey(path)
+			if err != nil {
+				return nil, fmt.Errorf("dkim: domain %q, selector %q: %s", profile.Domain, selector, err)
+			}
+			loaded.keys[selector] = key
+		}
+		this.profiles[profile.Domain] = loaded
+	}
+
+	return this, nil
+}
+
+// Sign добавляет заголовок DKIM-Signature к письму согласно профилю HostnameFrom.
+// Если профиль для домена не настроен, письмо возвращается без изменений
+func (this *Signer) Sign(message *common.MailMessage) (string, error) {
+	loaded, ok := this.profiles[message.HostnameFrom]
+	if !ok {
+		return message.Body, nil
+	}
+
+	selector := loaded.profile.activeSelector()
+	key, ok := loaded.keys[selector]
+	if !ok {
+		return "", fmt.Errorf("dkim: no private key loaded for selector %q of domain %q", selector, loaded.profile.Domain)
+	}
+
+	headers, body := splitHeadersAndBody(message.Body)
+	canon := loaded.profile.Canonicalization
+
+	bodyHash := sha256.Sum256([]byte(canonicalizeBody(body, canon)))
+	signedHeaderNames := signedHeaderList(loaded.profile, headers)
+
+	dkimHeader := buildUnsignedDKIMHeader(loaded.profile, selector, signedHeaderNames, bodyHash)
+
+	signingInput := buildSigningInput(headers, signedHeaderNames, canon, dkimHeader)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	dkimHeader += base64.StdEncoding.EncodeToString(signature)
+	return dkimHeader + "\r\n" + message.Body, nil
+}
+
+// splitHeadersAndBody разбивает письмо на заголовки и тело по первой пустой строке
+func splitHeadersAndBody(message string) ([]string, string) {
+	parts := strings.SplitN(message, "\r\n\r\n", 2)
+	headers := strings.Split(parts[0], "\r\n")
+	body := ""
+	if len(parts) == 2 {
+		body = parts[1]
+	}
+	return headers, body
+}
+
+// signedHeaderList объединяет присутствующие в письме заголовки из HeadersToSign
+// с всегда добавляемыми oversign заголовками
+func signedHeaderList(profile *Profile, headers []string) []string {
+	names := make([]string, 0, len(profile.HeadersToSign)+len(profile.OversignHeaders))
+	for _, name := range profile.HeadersToSign {
+		if rawLine, _ := findHeader(headers, name); len(rawLine) > 0 {
+			names = append(names, name)
+		}
+	}
+	for _, name := range profile.OversignHeaders {
+		found := false
+		for _, existing := range names {
+			if strings.EqualFold(existing, name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// buildUnsignedDKIMHeader собирает заголовок DKIM-Signature со всеми тегами, кроме b=,
+// который добавляется отдельно после подсчета подписи
+func buildUnsignedDKIMHeader(profile *Profile, selector string, headerNames []string, bodyHash [32]byte) string {
+	return fmt.Sprintf(
+		"DKIM-Signature: v=1; a=rsa-sha256; c=%s/%s; d=%s; s=%s; h=%s; bh=%s; b=",
+		profile.Canonicalization, profile.Canonicalization,
+		profile.Domain, selector,
+		strings.Join(headerNames, ":"),
+		base64.StdEncoding.EncodeToString(bodyHash[:]),
+	)
+}
+
+// buildSigningInput собирает данные, по которым считается подпись: канонизированные
+// подписываемые заголовки в порядке h=, затем сам DKIM-Signature заголовок без b=
+func buildSigningInput(headers []string, headerNames []string, canon Canonicalization, dkimHeaderWithoutSignature string) string {
+	var builder strings.Builder
+	for _, name := range headerNames {
+		rawLine, value := findHeader(headers, name)
+		canonicalized := canonicalizeHeader(rawLine, name, value, canon)
+		if len(canonicalized) > 0 {
+			builder.WriteString(canonicalized)
+			builder.WriteString("\r\n")
+		}
+	}
+	builder.WriteString(strings.TrimSuffix(dkimHeaderWithoutSignature, " "))
+	return builder.String()
+}
+
+// findHeader возвращает исходную строку и значение первого заголовка с данным именем,
+// не изменяя пробелы в значении - это важно для simple канонализации, которая должна
+// хешировать заголовок ровно такими байтами, какими он пришел в письме. Возвращает
+// пустые строки, если заголовка нет (используется для oversign заголовков,
+// которых еще может не быть в письме)
+func findHeader(headers []string, name string) (string, string) {
+	prefix := strings.ToLower(name) + ":"
+	for _, header := range headers {
+		if strings.HasPrefix(strings.ToLower(header), prefix) {
+			return header, header[len(prefix):]
+		}
+	}
+	return "", ""
+}
+
+// loadPrivateKey читает приватный ключ RSA из PEM файла
+func loadPrivateKey(path string) (*rsa.PrivateKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s does not contain an RSA private key", path)
+	}
+	return rsaKey, nil
+}
@@ -0,0 +1,89 @@
+package dkim
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/AdOnWeb/postmanq/common"
+)
+
+func TestSignerSignProducesSignatureVerifierAccepts(t *testing.T) {
+	key, err := GenerateKeyPair(1024)
+	if err != nil {
+		t.Fatalf("unexpected error generating key: %s", err)
+	}
+
+	keyFile, err := os.CreateTemp("", "dkim-signer-test-*.pem")
+	if err != nil {
+		t.Fatalf("unexpected error creating temp key file: %s", err)
+	}
+	defer os.Remove(keyFile.Name())
+	if _, err := keyFile.WriteString(EncodePrivateKeyPEM(key)); err != nil {
+		t.Fatalf("unexpected error writing temp key file: %s", err)
+	}
+	keyFile.Close()
+
+	record, err := DNSTXTRecord(key)
+	if err != nil {
+		t.Fatalf("unexpected error building DNS record: %s", err)
+	}
+
+	profile := &Profile{
+		Domain:          "example.com",
+		Selectors:       []string{"sel1"},
+		PrivateKeyFiles: map[string]string{"sel1": keyFile.Name()},
+	}
+
+	signer, err := NewSigner([]*Profile{profile})
+	if err != nil {
+		t.Fatalf("unexpected error creating signer: %s", err)
+	}
+
+	message := &common.MailMessage{
+		HostnameFrom: "example.com",
+		Body: "From: a@example.com\r\nTo: b@example.com\r\nSubject: test\r\n" +
+			"Date: Mon, 02 Jan 2006 15:04:05 -0700\r\nMessage-Id: <1@example.com>\r\n\r\nhello\r\n",
+	}
+
+	signed, err := signer.Sign(message)
+	if err != nil {
+		t.Fatalf("unexpected error signing message: %s", err)
+	}
+	if !strings.HasPrefix(signed, "DKIM-Signature:") {
+		t.Fatalf("expected signed message to start with DKIM-Signature header, got %q", signed)
+	}
+
+	previousLookup := lookupTXT
+	lookupTXT = func(name string) ([]string, error) {
+		if name != "sel1._domainkey.example.com" {
+			t.Fatalf("unexpected DNS lookup for %q", name)
+		}
+		return []string{record}, nil
+	}
+	defer func() { lookupTXT = previousLookup }()
+
+	ok, err := Verify(signed)
+	if err != nil {
+		t.Fatalf("unexpected error verifying signature: %s", err)
+	}
+	if !ok {
+		t.Fatal("expected signed message to verify successfully")
+	}
+}
+
+func TestSignerSignReturnsMessageUnchangedWhenNoProfileConfigured(t *testing.T) {
+	signer, err := NewSigner(nil)
+	if err != nil {
+		t.Fatalf("unexpected error creating signer: %s", err)
+	}
+
+	message := &common.MailMessage{HostnameFrom: "unconfigured.example.com", Body: "hello"}
+	signed, err := signer.Sign(message)
+	if err != nil {
+		t.Fatalf("unexpected error signing message: %s", err)
+	}
+	if signed != message.Body {
+		t.Fatalf("expected message to be returned unchanged, got %q", signed)
+	}
+}
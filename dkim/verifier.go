@@ -0,0 +1,130 @@
+package dkim
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Verify проверяет DKIM подпись сырого письма, используя публичный ключ,
+// опубликованный в DNS TXT записи selector._domainkey.domain.
+// Используется перед тем как доверять входящим bounce и feedback report письмам.
+// Проверяются только подписи в том виде, в каком их формирует Signer: заголовок
+// DKIM-Signature в одну строку, a=rsa-sha256 и теги v, a, c, d, s, h, bh, b в этом
+// порядке. Свернутый на несколько строк заголовок или другой порядок тегов дают false
+func Verify(rawMessage string) (bool, error) {
+	headers, body := splitHeadersAndBody(rawMessage)
+
+	_, dkimHeaderValue := findHeader(headers, "DKIM-Signature")
+	if len(dkimHeaderValue) == 0 {
+		return false, fmt.Errorf("dkim: message has no DKIM-Signature header")
+	}
+
+	tags := parseTags(dkimHeaderValue)
+	headerCanon, bodyCanon := splitCanonTag(tags["c"])
+
+	expectedBodyHash := sha256.Sum256([]byte(canonicalizeBody(body, bodyCanon)))
+	if base64.StdEncoding.EncodeToString(expectedBodyHash[:]) != tags["bh"] {
+		return false, nil
+	}
+
+	headerNames := strings.Split(tags["h"], ":")
+	// реконструируем заголовок без b=, используя уже разобранные теги, а не исходную
+	// строку - b= несет base64, в котором может случайно встретиться подстрока "b="
+	unsignedHeader := fmt.Sprintf(
+		"DKIM-Signature: v=1; a=rsa-sha256; c=%s/%s; d=%s; s=%s; h=%s; bh=%s; b=",
+		headerCanon, bodyCanon, tags["d"], tags["s"], tags["h"], tags["bh"],
+	)
+	signingInput := buildSigningInput(headers, headerNames, headerCanon, unsignedHeader)
+
+	signature, err := base64.StdEncoding.DecodeString(tags["b"])
+	if err != nil {
+		return false, fmt.Errorf("dkim: invalid b= tag: %s", err)
+	}
+
+	publicKey, err := fetchPublicKey(tags["s"], tags["d"])
+	if err != nil {
+		return false, err
+	}
+
+	digest := sha256.Sum256([]byte(signingInput))
+	err = rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature)
+	return err == nil, nil
+}
+
+// splitCanonTag разбирает тег c=header/body на канонализацию заголовков и тела,
+// по умолчанию обе части simple; если тег не содержит "/", он задает канонализацию
+// заголовков, а тело остается simple, как того требует RFC 6376
+func splitCanonTag(tag string) (header, body Canonicalization) {
+	if len(tag) == 0 {
+		return SimpleCanonicalization, SimpleCanonicalization
+	}
+
+	parts := strings.SplitN(tag, "/", 2)
+	header = Canonicalization(parts[0])
+	if len(parts) == 2 {
+		body = Canonicalization(parts[1])
+	} else {
+		body = SimpleCanonicalization
+	}
+	return header, body
+}
+
+// parseTags разбирает теги заголовка DKIM-Signature вида "v=1; a=rsa-sha256; ..."
+func parseTags(header string) map[string]string {
+	header = strings.TrimPrefix(header, "DKIM-Signature:")
+	tags := make(map[string]string)
+	for _, part := range strings.Split(header, ";") {
+		part = strings.TrimSpace(part)
+		if len(part) == 0 {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) == 2 {
+			tags[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	}
+	return tags
+}
+
+// lookupTXT ищет DNS TXT записи домена, вынесено в переменную, чтобы тесты
+// могли подменить реальный DNS-запрос и проверять подпись без сети
+var lookupTXT = net.LookupTXT
+
+// fetchPublicKey достает публичный ключ DKIM из DNS TXT записи селектора
+func fetchPublicKey(selector, domain string) (*rsa.PublicKey, error) {
+	name := selector + "._domainkey." + domain
+	records, err := lookupTXT(name)
+	if err != nil {
+		return nil, fmt.Errorf("dkim: lookup %s: %s", name, err)
+	}
+
+	for _, record := range records {
+		tags := parseTags("DKIM-Signature:" + strings.ReplaceAll(record, " ", ""))
+		encoded, ok := tags["p"]
+		if !ok {
+			continue
+		}
+
+		der, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			continue
+		}
+
+		key, err := x509.ParsePKIXPublicKey(der)
+		if err != nil {
+			continue
+		}
+		if rsaKey, ok := key.(*rsa.PublicKey); ok {
+			return rsaKey, nil
+		}
+	}
+
+	return nil, fmt.Errorf("dkim: no usable p= public key found for %s", name)
+}
+
@@ -0,0 +1,70 @@
+// Package inbound принимает письма, адресованные на обратный адрес,
+// сгенерированный postmanq (VERP), и разбирает в них DSN bounce
+// и ARF/FBL отчеты, чтобы превратить postmanq из fire-and-forget
+// отправителя в полноценный трекер статуса доставки.
+package inbound
+
+import (
+	"sync"
+	"time"
+)
+
+// Тип разобранного входящего отчета
+type BounceType int
+
+const (
+	// Не удалось определить тип отчета
+	UnknownBounceType BounceType = iota
+
+	// DSN message/delivery-status, почтовик сообщает об ошибке доставки
+	DeliveryStatusBounceType
+
+	// ARF/FBL message/feedback-report, получатель пожаловался на письмо как на спам
+	FeedbackReportBounceType
+)
+
+// BounceRecord - разобранный отчет о недоставке или жалобе, связанный с письмом,
+// которое postmanq когда-то отправил
+type BounceRecord struct {
+	// Идентификатор письма, для которого пришел этот отчет, из VERP обратного адреса
+	MessageId int64 `json:"messageId"`
+
+	// Тип отчета
+	Type BounceType `json:"type"`
+
+	// Код ошибки из DSN Status поля, например "5.1.1", пусто для feedback report
+	Status string `json:"status"`
+
+	// Диагностика почтовика в свободной форме, если есть
+	Diagnostic string `json:"diagnostic"`
+
+	// Когда отчет был получен и разобран
+	ReceivedAt time.Time `json:"receivedAt"`
+}
+
+// BounceStore хранит разобранные отчеты, чтобы downstream потребители могли их опросить
+type BounceStore struct {
+	mutex   sync.RWMutex
+	records map[int64][]*BounceRecord
+}
+
+// NewBounceStore создает пустое хранилище отчетов
+func NewBounceStore() *BounceStore {
+	this := new(BounceStore)
+	this.records = make(map[int64][]*BounceRecord)
+	return this
+}
+
+// Add сохраняет отчет под идентификатором письма, к которому он относится
+func (this *BounceStore) Add(record *BounceRecord) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.records[record.MessageId] = append(this.records[record.MessageId], record)
+}
+
+// ByMessageId возвращает все отчеты, пришедшие по заданному письму
+func (this *BounceStore) ByMessageId(messageId int64) []*BounceRecord {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return this.records[messageId]
+}
@@ -0,0 +1,138 @@
+package inbound
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/AdOnWeb/postmanq/common"
+	"github.com/AdOnWeb/postmanq/dkim"
+)
+
+// Настройки SMTP сервера, принимающего bounce письма
+type Config struct {
+	// Адрес, на котором слушать входящие SMTP соединения, например ":2525"
+	ListenAddr string `yaml:"listenAddr"`
+
+	// Имя сервера, которым он представляется в приветствии
+	Hostname string `yaml:"hostname"`
+}
+
+// Listener - минимальный SMTP сервер. Он принимает любую входящую почту, но на разбор
+// DSN/ARF отчетов передает только письма на обратные адреса, сгенерированные postmanq
+type Listener struct {
+	config Config
+	store  *BounceStore
+}
+
+// NewListener создает SMTP сервер, складывающий разобранные отчеты в store
+func NewListener(config Config, store *BounceStore) *Listener {
+	if len(config.Hostname) == 0 {
+		config.Hostname = "postmanq-inbound"
+	}
+
+	this := new(Listener)
+	this.config = config
+	this.store = store
+	return this
+}
+
+// Run запускает прием соединений, блокируется до ошибки listener-а
+func (this *Listener) Run() error {
+	listener, err := net.Listen("tcp", this.config.ListenAddr)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go this.handle(conn)
+	}
+}
+
+// handle проводит соединение через минимальный диалог SMTP и разбирает полученное письмо
+func (this *Listener) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Minute))
+
+	text := textproto.NewConn(conn)
+	text.PrintfLine("220 %s postmanq inbound", this.config.Hostname)
+
+	var rcptTo string
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+
+		command := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(command, "HELO"), strings.HasPrefix(command, "EHLO"):
+			text.PrintfLine("250 %s", this.config.Hostname)
+		case strings.HasPrefix(command, "MAIL FROM"):
+			text.PrintfLine("250 OK")
+		case strings.HasPrefix(command, "RCPT TO"):
+			rcptTo = extractAddress(line)
+			text.PrintfLine("250 OK")
+		case strings.HasPrefix(command, "DATA"):
+			text.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			raw, err := text.ReadDotBytes()
+			if err != nil {
+				text.PrintfLine("451 error reading message")
+				return
+			}
+			this.accept(rcptTo, raw)
+			text.PrintfLine("250 OK")
+		case strings.HasPrefix(command, "QUIT"):
+			text.PrintfLine("221 bye")
+			return
+		default:
+			text.PrintfLine("500 unrecognized command")
+		}
+	}
+}
+
+// accept разбирает письмо и, если обратный адрес получателя был сгенерирован postmanq,
+// проверяет его DKIM подпись и сохраняет отчет в BounceStore. VERP адрес легко
+// угадать (Id - это UnixNano), поэтому доверять содержимому можно только после того,
+// как подпись пришедшего письма подтверждена - иначе кто угодно сможет подделать
+// bounce или жалобу на чужое письмо
+func (this *Listener) accept(rcptTo string, raw []byte) {
+	messageId, err := common.DecodeVERPEnvelope(rcptTo)
+	if err != nil {
+		// письмо пришло не на наш VERP адрес, нам нечего с ним сопоставить
+		return
+	}
+
+	verified, err := dkim.Verify(string(raw))
+	if err != nil || !verified {
+		// не доверяем неподписанным или не прошедшим проверку bounce и feedback report письмам
+		return
+	}
+
+	record, err := ParseBounce(bufio.NewReader(bytes.NewReader(raw)))
+	if err != nil {
+		return
+	}
+
+	record.MessageId = messageId
+	record.ReceivedAt = time.Now()
+	this.store.Add(record)
+}
+
+// extractAddress достает адрес из команды вида "RCPT TO:<user@example.com>"
+func extractAddress(line string) string {
+	open := strings.Index(line, "<")
+	close := strings.Index(line, ">")
+	if open == -1 || close == -1 || close < open {
+		return ""
+	}
+	return line[open+1 : close]
+}
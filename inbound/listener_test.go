@@ -0,0 +1,28 @@
+package inbound
+
+import "testing"
+
+func TestAcceptDropsUnsignedBounce(t *testing.T) {
+	store := NewBounceStore()
+	listener := NewListener(Config{}, store)
+
+	rcptTo := "bounces+42@example.com"
+	raw := []byte("From: mailer-daemon@mx.example.com\r\n\r\nno DKIM signature here\r\n")
+
+	listener.accept(rcptTo, raw)
+
+	if records := store.ByMessageId(42); len(records) != 0 {
+		t.Fatalf("expected unsigned bounce to be dropped, got %d records", len(records))
+	}
+}
+
+func TestAcceptIgnoresNonVERPRecipient(t *testing.T) {
+	store := NewBounceStore()
+	listener := NewListener(Config{}, store)
+
+	listener.accept("plain@example.com", []byte("From: a@example.com\r\n\r\nbody\r\n"))
+
+	if records := store.ByMessageId(0); len(records) != 0 {
+		t.Fatalf("expected no records stored for a non-VERP recipient, got %d", len(records))
+	}
+}
@@ -0,0 +1,100 @@
+package inbound
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+)
+
+// ParseBounce разбирает сырое письмо, пришедшее на VERP обратный адрес, достает из него
+// DSN message/delivery-status или ARF message/feedback-report часть
+func ParseBounce(raw io.Reader) (*BounceRecord, error) {
+	message, err := mail.ReadMessage(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaType, params, err := mime.ParseMediaType(message.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		// письмо без DSN частей нам не интересно, возвращаем пустой отчет
+		return &BounceRecord{Type: UnknownBounceType}, nil
+	}
+
+	record := &BounceRecord{Type: UnknownBounceType}
+	reader := multipart.NewReader(message.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		partType, _, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if err != nil {
+			continue
+		}
+
+		switch partType {
+		case "message/delivery-status":
+			if err := fillDeliveryStatus(record, part); err != nil {
+				return nil, err
+			}
+		case "message/feedback-report":
+			if err := fillFeedbackReport(record, part); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return record, nil
+}
+
+// fillDeliveryStatus читает поля Status и Diagnostic-Code из DSN части; per-message и
+// per-recipient группы читаются вместе, при повторе поля берется последнее значение
+func fillDeliveryStatus(record *BounceRecord, part io.Reader) error {
+	fields, err := readDSNFields(part)
+	if err != nil {
+		return err
+	}
+
+	record.Type = DeliveryStatusBounceType
+	record.Status = fields["status"]
+	record.Diagnostic = fields["diagnostic-code"]
+	return nil
+}
+
+// fillFeedbackReport отмечает отчет как ARF/FBL жалобу
+func fillFeedbackReport(record *BounceRecord, part io.Reader) error {
+	fields, err := readDSNFields(part)
+	if err != nil {
+		return err
+	}
+
+	record.Type = FeedbackReportBounceType
+	record.Diagnostic = fields["feedback-type"]
+	return nil
+}
+
+// readDSNFields разбирает плоский список "Имя: значение" полей, которым являются
+// и message/delivery-status, и message/feedback-report части
+func readDSNFields(part io.Reader) (map[string]string, error) {
+	fields := make(map[string]string)
+	scanner := bufio.NewScanner(part)
+	for scanner.Scan() {
+		line := scanner.Text()
+		colon := strings.Index(line, ":")
+		if colon == -1 {
+			continue
+		}
+
+		name := strings.ToLower(strings.TrimSpace(line[:colon]))
+		value := strings.TrimSpace(line[colon+1:])
+		fields[name] = value
+	}
+	return fields, scanner.Err()
+}
@@ -0,0 +1,45 @@
+package inbound
+
+import (
+	"strings"
+	"testing"
+)
+
+const sampleDSN = "From: mailer-daemon@mx.example.com\r\n" +
+	"Content-Type: multipart/report; report-type=delivery-status; boundary=\"b\"\r\n" +
+	"\r\n" +
+	"--b\r\n" +
+	"Content-Type: text/plain\r\n\r\n" +
+	"bounce explanation\r\n" +
+	"--b\r\n" +
+	"Content-Type: message/delivery-status\r\n\r\n" +
+	"Status: 5.1.1\r\n" +
+	"Diagnostic-Code: smtp; 550 user unknown\r\n" +
+	"--b--\r\n"
+
+func TestParseBounceDeliveryStatus(t *testing.T) {
+	record, err := ParseBounce(strings.NewReader(sampleDSN))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if record.Type != DeliveryStatusBounceType {
+		t.Fatalf("expected DeliveryStatusBounceType, got %v", record.Type)
+	}
+	if record.Status != "5.1.1" {
+		t.Fatalf("expected status 5.1.1, got %q", record.Status)
+	}
+	if record.Diagnostic != "smtp; 550 user unknown" {
+		t.Fatalf("unexpected diagnostic: %q", record.Diagnostic)
+	}
+}
+
+func TestParseBouncePlainMessage(t *testing.T) {
+	record, err := ParseBounce(strings.NewReader("From: a@example.com\r\n\r\nhello\r\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if record.Type != UnknownBounceType {
+		t.Fatalf("expected UnknownBounceType for non-DSN message, got %v", record.Type)
+	}
+}